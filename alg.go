@@ -3,17 +3,80 @@
 package alg
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
+	"sync"
+	"time"
 )
 
+// ErrNotSupported is returned by Dial when the requested transformation
+// type and algorithm name are not registered with the running kernel's
+// crypto API.
+var ErrNotSupported = errors.New("alg: algorithm not supported by running kernel")
+
+// An AlgInfo describes a single algorithm registered with the running
+// kernel's crypto API, as reported by /proc/crypto.
+type AlgInfo struct {
+	Name       string
+	Driver     string
+	Module     string
+	Type       string
+	Priority   int
+	Internal   bool
+	Async      bool
+	BlockSize  int
+	DigestSize int
+	IVSize     int
+	KeySize    int
+}
+
+// Supported reports whether the given transformation type and algorithm
+// name are registered with the running kernel's crypto API.  typ is one
+// of the transformation types accepted by Dial, such as "hash",
+// "skcipher", or "aead".  It consults Algorithms, so if Algorithms
+// returns an error, Supported reports false.
+func Supported(typ, name string) bool {
+	algs, err := Algorithms()
+	if err != nil {
+		return false
+	}
+
+	for _, a := range algs {
+		if algTypeMatches(a.Type, typ) && a.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// algTypeMatches reports whether procType, a "type:" field value reported
+// by /proc/crypto, corresponds to typ, one of the transformation types
+// accepted by Dial.  The kernel reports hash algorithms as "shash" or
+// "ahash" depending on whether they have a synchronous or asynchronous
+// implementation, never as "hash" itself, so those are treated as
+// synonyms for typeHash.
+func algTypeMatches(procType, typ string) bool {
+	switch procType {
+	case "shash", "ahash":
+		procType = typeHash
+	}
+
+	return procType == typ
+}
+
 // A Conn is a connection to the Linux kernel crypto API, using an AF_ALG
-// socket.  A Conn can be used to initialize Hashes via its Hash method,
-// using the parameters configured in Dial.
+// socket.  A Conn can be used to initialize Hashes via its Hash method, or
+// Ciphers via its Cipher method, using the parameters configured in Dial.
 type Conn struct {
 	size      int
 	blockSize int
+	ivSize    int
+	assocSize int
+	authSize  int
 
 	c *conn
 }
@@ -22,6 +85,17 @@ type Conn struct {
 type Config struct {
 	Feature uint32
 	Mask    uint32
+
+	// Key specifies key material for keyed algorithms.  Key is required
+	// when dialing the skcipher and aead transformation types, and for
+	// keyed hash algorithms such as hmac(sha256) and cmac(aes).
+	Key []byte
+
+	// AssocDataSize and AuthSize specify the associated data and
+	// authentication tag sizes, in bytes, used by aead transformations.
+	// They are ignored for other transformation types.
+	AssocDataSize int
+	AuthSize      int
 }
 
 // Dial dials a connection the Linux kernel crypto API, using the specified
@@ -29,17 +103,31 @@ type Config struct {
 // is nil, a default configuration will be used.
 //
 // At this time, the following transformation types and algorithm types are
-// supported:
+// built in, and more can be added at runtime using RegisterHash:
 //   - hash
 //     - md5
-//     - sha1
-//     - sha256
+//     - sha1, sha224, sha256, sha384, sha512
+//     - sha3-224, sha3-256, sha3-384, sha3-512
+//     - hmac(md5), hmac(sha1), hmac(sha256), hmac(sha384), hmac(sha512)
+//     - cmac(aes)
+//   - skcipher
+//     - cbc(aes)
+//   - aead
+//     - gcm(aes)
+//
+// Keyed hash algorithms, such as hmac(sha256), require Config.Key to be
+// set.
+//
+// Not every algorithm above is necessarily registered with the running
+// kernel; use Supported or Algorithms to check at runtime.  If the
+// requested transformation isn't registered, Dial returns an error
+// wrapping ErrNotSupported.
 func Dial(typ, name string, config *Config) (*Conn, error) {
 	if config == nil {
 		config = &Config{}
 	}
 
-	var size, blockSize int
+	var size, blockSize, ivSize, assocSize, authSize int
 	var ok bool
 
 	switch typ {
@@ -48,6 +136,14 @@ func Dial(typ, name string, config *Config) (*Conn, error) {
 		if !ok {
 			return nil, fmt.Errorf("alg: unknown hash algorithm %q", name)
 		}
+	case typeSkcipher, typeAead:
+		ivSize, ok = cipherIVSizes(name)
+		if !ok {
+			return nil, fmt.Errorf("alg: unknown cipher algorithm %q", name)
+		}
+		if typ == typeAead {
+			assocSize, authSize = config.AssocDataSize, config.AuthSize
+		}
 	default:
 		return nil, fmt.Errorf("alg: transformation type %q unsupported", typ)
 	}
@@ -61,17 +157,68 @@ func Dial(typ, name string, config *Config) (*Conn, error) {
 	return &Conn{
 		size:      size,
 		blockSize: blockSize,
+		ivSize:    ivSize,
+		assocSize: assocSize,
+		authSize:  authSize,
 
 		c: c,
 	}, nil
 }
 
+// DialContext is like Dial, but allows ctx to cancel the dial operation.
+func DialContext(ctx context.Context, typ, name string, config *Config) (*Conn, error) {
+	type result struct {
+		c   *Conn
+		err error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		c, err := Dial(typ, name, config)
+		done <- result{c: c, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-done:
+		return r.c, r.err
+	}
+}
+
 // Hash creates a Hash handle from a Conn.  The handle is not safe for
 // concurrent use.
 func (c *Conn) Hash() (Hash, error) {
 	return c.c.Hash(c.size, c.blockSize)
 }
 
+// HashContext is like Hash, but once ctx is done, Write calls on the
+// returned Hash return an error and Sum calls panic, as Sum does on any
+// read error.
+func (c *Conn) HashContext(ctx context.Context) (Hash, error) {
+	return c.c.HashContext(ctx, c.size, c.blockSize)
+}
+
+// Cipher creates a Cipher handle from a Conn.  The handle is not safe for
+// concurrent use.
+func (c *Conn) Cipher() (Cipher, error) {
+	return c.c.Cipher(c.ivSize, c.assocSize, c.authSize)
+}
+
+// CipherContext is like Cipher, but the returned Cipher's in-flight
+// Encrypt and Decrypt calls are unblocked with an error as soon as ctx is
+// done.
+func (c *Conn) CipherContext(ctx context.Context) (Cipher, error) {
+	return c.c.CipherContext(ctx, c.ivSize, c.assocSize, c.authSize)
+}
+
+// HashPool creates a BatchHasher backed by a pool of n accepted worker
+// sockets, allowing independent hashing jobs to be dispatched across
+// multiple CPU cores or crypto accelerator queues concurrently.
+func (c *Conn) HashPool(n int) (BatchHasher, error) {
+	return c.c.HashPool(n, c.size, c.blockSize)
+}
+
 // Close closes the connection.
 func (c *Conn) Close() error {
 	return c.c.Close()
@@ -83,6 +230,70 @@ func (c *Conn) Close() error {
 type Hash interface {
 	hash.Hash
 	io.Closer
+
+	// SetDeadline, SetReadDeadline, and SetWriteDeadline set deadlines
+	// on the Hash's socket, as with net.Conn.  The read deadline applies
+	// to future Sum calls; Write does not observe the write deadline.
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// A Cipher is a symmetric cipher handle, used to encrypt and decrypt data
+// with the Linux kernel crypto API.  The Cipher's Close method must be
+// called to release its resources when it is no longer needed.
+type Cipher interface {
+	// SetKey changes the key used for future Encrypt and Decrypt calls.
+	SetKey(key []byte) error
+
+	// Encrypt encrypts plaintext using the given initialization vector,
+	// and returns the resulting ciphertext.  aad is the associated data
+	// to authenticate for aead transformations such as gcm(aes); its
+	// length must equal the AssocDataSize passed to Dial, and it is
+	// ignored for skcipher transformations.
+	Encrypt(iv, aad, plaintext []byte) ([]byte, error)
+
+	// Decrypt decrypts ciphertext using the given initialization vector,
+	// and returns the resulting plaintext.  aad is the associated data
+	// to authenticate for aead transformations such as gcm(aes); its
+	// length must equal the AssocDataSize passed to Dial, and it is
+	// ignored for skcipher transformations.
+	Decrypt(iv, aad, ciphertext []byte) ([]byte, error)
+
+	io.Closer
+
+	// SetDeadline, SetReadDeadline, and SetWriteDeadline set deadlines
+	// on the Cipher's socket, as with net.Conn.  The read deadline
+	// applies to reading back the result of future Encrypt and Decrypt
+	// calls; sending their input does not observe the write deadline.
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// A Result is the outcome of a single hashing job submitted to a
+// BatchHasher.
+type Result struct {
+	// ID is the identifier passed to Submit for this job.
+	ID uint64
+
+	// Sum is the resulting digest, if Err is nil.
+	Sum []byte
+
+	// Err is any error which occurred while processing the job.
+	Err error
+}
+
+// A BatchHasher dispatches independent hashing jobs across a pool of
+// worker sockets, so that a caller can saturate multiple CPU cores or
+// crypto accelerator queues from a single Conn.  Use its Close method to
+// release its resources when it is no longer needed.
+type BatchHasher interface {
+	// Submit queues data to be hashed under the given id, and returns a
+	// channel which receives exactly one Result once the job completes.
+	Submit(id uint64, data []byte) <-chan Result
+
+	io.Closer
 }
 
 // MD5 is a convenience function for use in Dial, to open a Conn that produces
@@ -103,27 +314,121 @@ func SHA256() (string, string, *Config) {
 	return typeHash, nameSHA256, nil
 }
 
+// HMAC_SHA256 is a convenience function for use in Dial, to open a Conn
+// that produces keyed HMAC-SHA256 Hashes, using key.
+func HMAC_SHA256(key []byte) (string, string, *Config) {
+	return typeHash, nameHMACSHA256, &Config{Key: key}
+}
+
+// AES_CBC is a convenience function for use in Dial, to open a Conn that
+// performs AES encryption and decryption in CBC mode, using key.
+func AES_CBC(key []byte) (string, string, *Config) {
+	return typeSkcipher, nameCBCAES, &Config{Key: key}
+}
+
+// AES_GCM is a convenience function for use in Dial, to open a Conn that
+// performs AES encryption and decryption in GCM mode, using key.  aadLen
+// and tagLen specify the sizes, in bytes, of the associated data and
+// authentication tag used by the AEAD transformation.
+func AES_GCM(key []byte, aadLen, tagLen int) (string, string, *Config) {
+	return typeAead, nameGCMAES, &Config{
+		Key:           key,
+		AssocDataSize: aadLen,
+		AuthSize:      tagLen,
+	}
+}
+
 const (
 	// Transformation types.
-	typeHash = "hash"
+	typeHash     = "hash"
+	typeSkcipher = "skcipher"
+	typeAead     = "aead"
 
 	// Algorithm names.
-	nameMD5    = "md5"
-	nameSHA1   = "sha1"
-	nameSHA256 = "sha256"
+	nameMD5        = "md5"
+	nameSHA1       = "sha1"
+	nameSHA224     = "sha224"
+	nameSHA256     = "sha256"
+	nameSHA384     = "sha384"
+	nameSHA512     = "sha512"
+	nameSHA3_224   = "sha3-224"
+	nameSHA3_256   = "sha3-256"
+	nameSHA3_384   = "sha3-384"
+	nameSHA3_512   = "sha3-512"
+	nameHMACMD5    = "hmac(md5)"
+	nameHMACSHA1   = "hmac(sha1)"
+	nameHMACSHA256 = "hmac(sha256)"
+	nameHMACSHA384 = "hmac(sha384)"
+	nameHMACSHA512 = "hmac(sha512)"
+	nameCMACAES    = "cmac(aes)"
+	nameCBCAES     = "cbc(aes)"
+	nameGCMAES     = "gcm(aes)"
+)
+
+// A hashInfo describes the digest and block sizes of a hash algorithm
+// registered with the package.
+type hashInfo struct {
+	size      int
+	blockSize int
+}
+
+var (
+	hashRegistryMu sync.RWMutex
+
+	// hashRegistry holds the built-in hash algorithms known to the
+	// package.  It can be extended at runtime via RegisterHash.
+	hashRegistry = map[string]hashInfo{
+		nameMD5:        {16, 64},
+		nameSHA1:       {20, 64},
+		nameSHA224:     {28, 64},
+		nameSHA256:     {32, 64},
+		nameSHA384:     {48, 128},
+		nameSHA512:     {64, 128},
+		nameSHA3_224:   {28, 144},
+		nameSHA3_256:   {32, 136},
+		nameSHA3_384:   {48, 104},
+		nameSHA3_512:   {64, 72},
+		nameHMACMD5:    {16, 64},
+		nameHMACSHA1:   {20, 64},
+		nameHMACSHA256: {32, 64},
+		nameHMACSHA384: {48, 128},
+		nameHMACSHA512: {64, 128},
+		nameCMACAES:    {16, 16},
+	}
 )
 
+// RegisterHash registers a hash algorithm by the name used by the Linux
+// kernel crypto API, along with its digest and block sizes, so that it can
+// be used with Dial.  This allows callers to make use of hash algorithms
+// registered in the running kernel but not built into this package,
+// without waiting for a new release.
+func RegisterHash(name string, size, blockSize int) {
+	hashRegistryMu.Lock()
+	defer hashRegistryMu.Unlock()
+
+	hashRegistry[name] = hashInfo{size: size, blockSize: blockSize}
+}
+
 // hashSizes looks up a hash by its name and returns its size and block
 // size, if available.  If the hash is not found, false will be returned.
 func hashSizes(name string) (size, blockSize int, ok bool) {
+	hashRegistryMu.RLock()
+	defer hashRegistryMu.RUnlock()
+
+	info, ok := hashRegistry[name]
+	return info.size, info.blockSize, ok
+}
+
+// cipherIVSizes looks up a skcipher or aead transformation by its name and
+// returns its initialization vector size, if available.  If the algorithm
+// is not found, false will be returned.
+func cipherIVSizes(name string) (ivSize int, ok bool) {
 	switch name {
-	case nameMD5:
-		return 16, 64, true
-	case nameSHA1:
-		return 20, 64, true
-	case nameSHA256:
-		return 32, 64, true
+	case nameCBCAES:
+		return 16, true
+	case nameGCMAES:
+		return 12, true
 	}
 
-	return 0, 0, false
+	return 0, false
 }
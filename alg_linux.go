@@ -3,14 +3,26 @@
 package alg
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
 
+// errBatchHasherClosed is returned to any job submitted to a BatchHasher
+// after it has been closed.
+var errBatchHasherClosed = errors.New("alg: batch hasher closed")
+
 const defaultSocketBufferSize = 64 * 1024
 
 // A conn is the internal connection type for Linux.
@@ -28,6 +40,13 @@ type socket interface {
 	FD() int
 	Read(p []byte) (int, error)
 	Sendto(p []byte, flags int, to unix.Sockaddr) error
+	Sendmsg(p, oob []byte, flags int) error
+	SetsockoptKey(key []byte) error
+	SetsockoptAEADAuthSize(size int) error
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	Cancel() error
 }
 
 // dial is the entry point for Dial. dial opens an AF_ALG socket
@@ -45,13 +64,36 @@ func dial(typ, name string, config *Config) (*conn, error) {
 		Mask:    config.Mask,
 	}
 
-	return bind(&sysSocket{fd: fd}, addr)
+	c, err := bind(&sysSocket{fd: fd, dupFD: -1}, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keyed algorithms (skcipher, aead, keyed hashes) require their key
+	// to be set on the bound socket before it is Accepted.
+	if len(config.Key) > 0 {
+		if err := c.s.SetsockoptKey(config.Key); err != nil {
+			return nil, err
+		}
+	}
+
+	if typ == typeAead && config.AuthSize > 0 {
+		if err := c.s.SetsockoptAEADAuthSize(config.AuthSize); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
 }
 
 // bind binds an AF_ALG socket using the input socket, which may be
 // a system call implementation or a mocked one for tests.
 func bind(s socket, addr *unix.SockaddrALG) (*conn, error) {
 	if err := s.Bind(addr); err != nil {
+		if err == unix.ENOENT {
+			return nil, fmt.Errorf("alg: %w: transformation %q, algorithm %q",
+				ErrNotSupported, addr.Type, addr.Name)
+		}
 		return nil, err
 	}
 
@@ -89,6 +131,210 @@ func (c *conn) Hash(size, blockSize int) (Hash, error) {
 	}, nil
 }
 
+// HashContext is like Hash, but once ctx is done, Write calls on the
+// returned Hash return an error and Sum calls panic, as Sum does on any
+// read error.
+func (c *conn) HashContext(ctx context.Context, size, blockSize int) (Hash, error) {
+	h, err := c.Hash(size, blockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ih := h.(*ihash)
+	return &ctxHash{ihash: ih, ctx: ctx, stop: watchCancel(ctx, ih.s)}, nil
+}
+
+// watchCancel spawns a goroutine which calls s.Cancel once ctx is done,
+// unblocking any in-flight operation on s. The returned channel must be
+// closed once s is closed, so the goroutine can exit even if ctx is never
+// done.
+func watchCancel(ctx context.Context, s socket) chan<- struct{} {
+	stop := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Cancel()
+		case <-stop:
+		}
+	}()
+
+	return stop
+}
+
+// A ctxHash wraps an ihash so that its socket is canceled once a Context
+// passed to HashContext is done.
+type ctxHash struct {
+	*ihash
+	ctx  context.Context
+	stop chan<- struct{}
+}
+
+var _ Hash = &ctxHash{}
+
+// Close stops the Context-watching goroutine and closes the underlying
+// ihash.
+func (h *ctxHash) Close() error {
+	close(h.stop)
+	return h.ihash.Close()
+}
+
+// Write returns ctx's error without writing once ctx is done, and
+// otherwise writes b to the underlying ihash.
+func (h *ctxHash) Write(b []byte) (int, error) {
+	if err := h.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return h.ihash.Write(b)
+}
+
+// HashPool creates a BatchHasher backed by n accepted worker sockets.
+func (c *conn) HashPool(n, size, blockSize int) (BatchHasher, error) {
+	workers := make([]*ihash, 0, n)
+	for i := 0; i < n; i++ {
+		h, err := c.Hash(size, blockSize)
+		if err != nil {
+			for _, w := range workers {
+				w.Close()
+			}
+			return nil, err
+		}
+		workers = append(workers, h.(*ihash))
+	}
+
+	bh := &batchHasher{
+		jobs: make(chan batchJob),
+		done: make(chan struct{}),
+	}
+
+	for _, w := range workers {
+		bh.wg.Add(1)
+		go bh.worker(w)
+	}
+
+	return bh, nil
+}
+
+// A batchJob is a single unit of work submitted to a batchHasher.
+type batchJob struct {
+	id     uint64
+	data   []byte
+	result chan Result
+}
+
+// A batchHasher is the internal Linux implementation of BatchHasher. It
+// dispatches jobs to a pool of worker goroutines, each bound to its own
+// accepted operation socket, so that independent hashing jobs can proceed
+// concurrently.
+type batchHasher struct {
+	jobs chan batchJob
+	done chan struct{}
+	wg   sync.WaitGroup
+
+	closeOnce sync.Once
+}
+
+var _ BatchHasher = &batchHasher{}
+
+// Submit queues data to be hashed under id, dispatching it to the next
+// available worker.
+func (bh *batchHasher) Submit(id uint64, data []byte) <-chan Result {
+	result := make(chan Result, 1)
+
+	select {
+	case bh.jobs <- batchJob{id: id, data: data, result: result}:
+	case <-bh.done:
+		result <- Result{ID: id, Err: errBatchHasherClosed}
+	}
+
+	return result
+}
+
+// Close stops all workers, waiting for any in-flight job to complete.
+func (bh *batchHasher) Close() error {
+	bh.closeOnce.Do(func() { close(bh.done) })
+	bh.wg.Wait()
+	return nil
+}
+
+// worker processes jobs sequentially on its own accepted socket h, until
+// bh is closed.
+func (bh *batchHasher) worker(h *ihash) {
+	defer bh.wg.Done()
+	defer h.Close()
+
+	for {
+		select {
+		case job := <-bh.jobs:
+			sum, err := bh.hash(h, job.data)
+			job.result <- Result{ID: job.id, Sum: sum, Err: err}
+		case <-bh.done:
+			return
+		}
+	}
+}
+
+// hash writes data to h and reads back the finalized digest, without the
+// panic-on-error behavior of ihash.Sum.
+func (bh *batchHasher) hash(h *ihash, data []byte) ([]byte, error) {
+	if _, err := h.Write(data); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 128)
+	n, err := h.s.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+// Cipher creates a new Cipher handle by accepting a single connection and
+// setting up an icipher.
+func (c *conn) Cipher(ivSize, assocSize, authSize int) (Cipher, error) {
+	s, err := c.s.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &icipher{
+		s:         s,
+		ivSize:    ivSize,
+		assocSize: assocSize,
+		authSize:  authSize,
+	}, nil
+}
+
+// CipherContext is like Cipher, but the returned Cipher's in-flight
+// Encrypt and Decrypt calls are unblocked with an error as soon as ctx is
+// done.
+func (c *conn) CipherContext(ctx context.Context, ivSize, assocSize, authSize int) (Cipher, error) {
+	ci, err := c.Cipher(ivSize, assocSize, authSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ic := ci.(*icipher)
+	return &ctxCipher{icipher: ic, stop: watchCancel(ctx, ic.s)}, nil
+}
+
+// A ctxCipher wraps an icipher so that its socket is canceled once a
+// Context passed to CipherContext is done.
+type ctxCipher struct {
+	*icipher
+	stop chan<- struct{}
+}
+
+var _ Cipher = &ctxCipher{}
+
+// Close stops the Context-watching goroutine and closes the underlying
+// icipher.
+func (c *ctxCipher) Close() error {
+	close(c.stop)
+	return c.icipher.Close()
+}
+
 var _ Hash = &ihash{}
 
 // An ihash is the internal Linux implementation of Hash.
@@ -108,11 +354,8 @@ func (h *ihash) Close() error {
 }
 
 func (h *ihash) ReadFrom(r io.Reader) (int64, error) {
-	if f, ok := r.(*os.File); ok {
-		if w, err, handled := h.sendfile(f, -1); handled {
-			return w, err
-		}
-		if w, err, handled := h.splice(f, -1); handled {
+	if sc, ok := r.(syscall.Conn); ok {
+		if w, err, handled := h.readFromSyscallConn(sc, -1); handled {
 			return w, err
 		}
 	}
@@ -123,104 +366,124 @@ func (h *ihash) ReadFrom(r io.Reader) (int64, error) {
 }
 
 func (h *ihash) readFromLimitedReader(lr *io.LimitedReader) (int64, error) {
-	if f, ok := lr.R.(*os.File); ok {
-		if w, err, handled := h.sendfile(f, lr.N); handled {
-			return w, err
-		}
-		if w, err, handled := h.splice(f, lr.N); handled {
+	if sc, ok := lr.R.(syscall.Conn); ok {
+		if w, err, handled := h.readFromSyscallConn(sc, lr.N); handled {
 			return w, err
 		}
 	}
 	return genericReadFrom(h, lr)
 }
 
-func (h *ihash) splice(f *os.File, remain int64) (written int64, err error, handled bool) {
-	offset, err := f.Seek(0, io.SeekCurrent)
-	if err != nil {
+// readFromSyscallConn attempts a zero-copy transfer of up to remain bytes
+// (or until EOF, if remain is -1) from sc directly into the AF_ALG socket,
+// preferring sendfile for regular files and falling back to splice
+// otherwise.
+func (h *ihash) readFromSyscallConn(sc syscall.Conn, remain int64) (written int64, err error, handled bool) {
+	rc, rcErr := sc.SyscallConn()
+	if rcErr != nil {
 		return 0, nil, false
 	}
-	fi, err := f.Stat()
-	if err != nil {
-		return 0, nil, false
-	}
-	if remain == -1 {
-		remain = fi.Size() - offset
-	}
-	// mmap must align on a page boundary
-	// mmap from 0, use data from offset
-	mmap, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()),
-		syscall.PROT_READ, syscall.MAP_SHARED)
-	if err != nil {
-		return 0, nil, false
-	}
-	defer syscall.Munmap(mmap)
-	bytes := mmap[offset : offset+remain]
-	var (
-		total = len(bytes)
-		start = 0
-		end   = defaultSocketBufferSize
-	)
 
-	if end > total {
-		end = total
-	}
-	for {
-		n, err := h.Write(bytes[start:end])
-		if err != nil {
-			return int64(start + n), err, true
+	cerr := rc.Read(func(fd uintptr) bool {
+		w, serr, ok := h.sendfile(int(fd), remain)
+		if !ok {
+			w, serr, ok = h.splice(int(fd), remain)
 		}
-		start += n
-		if start >= total {
-			break
+
+		written += w
+		if remain > 0 {
+			remain -= w
 		}
-		end += n
-		if end > total {
-			end = total
+		handled = ok
+
+		if serr == unix.EAGAIN {
+			// sc's fd isn't ready for reading yet; tell the runtime
+			// poller to wait for readability and call us again,
+			// rather than treating EAGAIN as a fatal error.
+			return false
 		}
+
+		err = serr
+		return true
+	})
+	if cerr != nil && err == nil {
+		err, handled = cerr, true
 	}
-	return remain, nil, true
+
+	return written, err, handled
 }
 
-func (h *ihash) sendfile(f *os.File, remain int64) (written int64, err error, handled bool) {
-	offset, err := f.Seek(0, io.SeekCurrent)
-	if err != nil {
+// sendfile transfers up to remain bytes (or until EOF, if remain is -1)
+// from the regular file referenced by fd into the AF_ALG socket using the
+// sendfile system call. It returns handled as false if fd does not refer
+// to a regular file, so the caller can fall back to splice.
+func (h *ihash) sendfile(fd int, remain int64) (written int64, err error, handled bool) {
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil || st.Mode&unix.S_IFMT != unix.S_IFREG {
 		return 0, nil, false
 	}
-	fi, err := f.Stat()
+
+	offset, err := unix.Seek(fd, 0, io.SeekCurrent)
 	if err != nil {
 		return 0, nil, false
 	}
 	if remain == -1 {
-		remain = fi.Size() - offset
+		remain = st.Size - offset
 	}
-	sc, err := f.SyscallConn()
-	if err != nil {
-		return 0, nil, false
-	}
-	var (
-		n    int
-		werr error
-	)
-	err = sc.Read(func(fd uintptr) bool {
-		for {
-			n, werr = syscall.Sendfile(h.s.FD(), int(fd), &offset, int(remain))
-			written += int64(n)
-			if werr != nil {
-				break
-			}
-			if int64(n) >= remain {
-				break
-			}
-			remain -= int64(n)
+
+	var n int64
+	for remain > 0 {
+		n, err = retryEAGAIN(h.s.FD(), func() (int64, error) {
+			n, serr := syscall.Sendfile(h.s.FD(), fd, &offset, int(remain))
+			return int64(n), serr
+		})
+		written += n
+		if err != nil || n == 0 {
+			break
 		}
-		return true
-	})
-	if err == nil {
-		err = werr
+		remain -= n
 	}
+
 	return written, err, true
 }
 
+// splice transfers up to remain bytes (or until EOF, if remain is -1) from
+// fd into the AF_ALG socket, bouncing the data through an internal pipe
+// using the splice system call, in chunks bounded by
+// defaultSocketBufferSize.
+func (h *ihash) splice(fd int, remain int64) (written int64, err error, handled bool) {
+	unbounded := remain == -1
+
+	for unbounded || remain > 0 {
+		size := defaultSocketBufferSize
+		if !unbounded && int64(size) > remain {
+			size = int(remain)
+		}
+
+		n, serr := h.pipes[1].SpliceFrom(fd, size, unix.SPLICE_F_MOVE)
+		if serr != nil {
+			return written, serr, true
+		}
+		if n == 0 {
+			break
+		}
+
+		m, werr := retryEAGAIN(h.s.FD(), func() (int64, error) {
+			return h.pipes[0].Splice(h.s.FD(), int(n), unix.SPLICE_F_MOVE|unix.SPLICE_F_MORE)
+		})
+		written += m
+		if werr != nil {
+			return written, werr, true
+		}
+
+		if !unbounded {
+			remain -= n
+		}
+	}
+
+	return written, nil, true
+}
+
 // Write writes data to an AF_ALG socket, but instructs the kernel
 // not to finalize the hash.
 func (h *ihash) Write(b []byte) (int, error) {
@@ -228,7 +491,9 @@ func (h *ihash) Write(b []byte) (int, error) {
 	if err != nil {
 		return n, err
 	}
-	_, err = h.pipes[0].Splice(h.s.FD(), n, unix.SPLICE_F_MOVE|unix.SPLICE_F_MORE)
+	_, err = retryEAGAIN(h.s.FD(), func() (int64, error) {
+		return h.pipes[0].Splice(h.s.FD(), n, unix.SPLICE_F_MOVE|unix.SPLICE_F_MORE)
+	})
 	return n, err
 }
 
@@ -252,9 +517,212 @@ func (h *ihash) BlockSize() int { return h.blockSize }
 // Size returns the size of the hash.
 func (h *ihash) Size() int { return h.size }
 
+// SetDeadline sets the read and write deadlines of the socket.  Only the
+// read deadline takes effect, on future Sum calls; Write does not observe
+// the write deadline.
+func (h *ihash) SetDeadline(t time.Time) error { return h.s.SetDeadline(t) }
+
+// SetReadDeadline sets the deadline for future Sum calls.
+func (h *ihash) SetReadDeadline(t time.Time) error { return h.s.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the write deadline of the socket.  Write does not
+// observe it, since it sends data with splice on the socket's duplicate fd.
+func (h *ihash) SetWriteDeadline(t time.Time) error { return h.s.SetWriteDeadline(t) }
+
+var _ Cipher = &icipher{}
+
+// An icipher is the internal Linux implementation of Cipher.
+type icipher struct {
+	s socket
+
+	ivSize    int
+	assocSize int
+	authSize  int
+}
+
+// Close closes the icipher's socket.
+func (c *icipher) Close() error {
+	return c.s.Close()
+}
+
+// SetKey changes the key used for future Encrypt and Decrypt calls.
+func (c *icipher) SetKey(key []byte) error {
+	return c.s.SetsockoptKey(key)
+}
+
+// Encrypt encrypts plaintext using the given initialization vector and
+// associated data.
+func (c *icipher) Encrypt(iv, aad, plaintext []byte) ([]byte, error) {
+	return c.crypt(unix.ALG_OP_ENCRYPT, iv, aad, plaintext)
+}
+
+// Decrypt decrypts ciphertext using the given initialization vector and
+// associated data.
+func (c *icipher) Decrypt(iv, aad, ciphertext []byte) ([]byte, error) {
+	return c.crypt(unix.ALG_OP_DECRYPT, iv, aad, ciphertext)
+}
+
+// SetDeadline sets the read and write deadlines of the socket.  Only the
+// read deadline takes effect, on reading back the result of future Encrypt
+// and Decrypt calls.
+func (c *icipher) SetDeadline(t time.Time) error { return c.s.SetDeadline(t) }
+
+// SetReadDeadline sets the read deadline for reading back the result of
+// future Encrypt and Decrypt calls.
+func (c *icipher) SetReadDeadline(t time.Time) error { return c.s.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the write deadline of the socket.  Encrypt and
+// Decrypt do not observe it, since they send their input with sendmsg on
+// the socket's duplicate fd.
+func (c *icipher) SetWriteDeadline(t time.Time) error { return c.s.SetWriteDeadline(t) }
+
+// algSetOp builds a SOL_ALG/ALG_SET_OP control message specifying whether
+// a subsequent Sendmsg performs an encrypt or decrypt operation, per the
+// cmsg layout documented in Documentation/crypto/userspace-if.rst in the
+// Linux kernel source.
+func algSetOp(op int) []byte {
+	const datalen = 4
+
+	b := make([]byte, unix.CmsgSpace(datalen))
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = unix.SOL_ALG
+	h.Type = unix.ALG_SET_OP
+	h.SetLen(unix.CmsgLen(datalen))
+
+	*(*uint32)(unsafe.Pointer(&b[unix.CmsgLen(0)])) = uint32(op)
+
+	return b
+}
+
+// algSetIV builds a SOL_ALG/ALG_SET_IV control message carrying iv, using
+// the kernel's af_alg_iv layout: a 4 byte length prefix followed by the
+// IV bytes themselves.
+func algSetIV(iv []byte) []byte {
+	datalen := 4 + len(iv)
+
+	b := make([]byte, unix.CmsgSpace(datalen))
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = unix.SOL_ALG
+	h.Type = unix.ALG_SET_IV
+	h.SetLen(unix.CmsgLen(datalen))
+
+	data := b[unix.CmsgLen(0):]
+	*(*uint32)(unsafe.Pointer(&data[0])) = uint32(len(iv))
+	copy(data[4:], iv)
+
+	return b
+}
+
+// algSetAEADAssoclen builds a SOL_ALG/ALG_SET_AEAD_ASSOCLEN control
+// message specifying the number of leading bytes of a subsequent
+// Sendmsg's payload that make up the AEAD associated data.
+func algSetAEADAssoclen(assocLen uint32) []byte {
+	const datalen = 4
+
+	b := make([]byte, unix.CmsgSpace(datalen))
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = unix.SOL_ALG
+	h.Type = unix.ALG_SET_AEAD_ASSOCLEN
+	h.SetLen(unix.CmsgLen(datalen))
+
+	*(*uint32)(unsafe.Pointer(&b[unix.CmsgLen(0)])) = assocLen
+
+	return b
+}
+
+// crypt performs an encrypt or decrypt operation, depending on op, by
+// sending the associated data concatenated with the plaintext or
+// ciphertext along with ALG_SET_OP and ALG_SET_IV control messages, and
+// reading back the result.  For aead transformations, the kernel echoes
+// the associated data back as a prefix of the result, so crypt strips it
+// before returning.
+func (c *icipher) crypt(op int, iv, aad, in []byte) ([]byte, error) {
+	if len(iv) != c.ivSize {
+		return nil, fmt.Errorf("alg: invalid IV size: %d bytes, expected %d bytes", len(iv), c.ivSize)
+	}
+	if c.assocSize > 0 && len(aad) != c.assocSize {
+		return nil, fmt.Errorf("alg: invalid associated data size: %d bytes, expected %d bytes", len(aad), c.assocSize)
+	}
+
+	oob := algSetOp(op)
+	oob = append(oob, algSetIV(iv)...)
+
+	payload := in
+	if c.assocSize > 0 {
+		oob = append(oob, algSetAEADAssoclen(uint32(c.assocSize))...)
+
+		payload = make([]byte, 0, len(aad)+len(in))
+		payload = append(payload, aad...)
+		payload = append(payload, in...)
+	}
+
+	if _, err := retryEAGAIN(c.s.FD(), func() (int64, error) {
+		return 0, c.s.Sendmsg(payload, oob, 0)
+	}); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(payload)+c.authSize)
+	n, err := c.s.Read(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return out[c.assocSize:n], nil
+}
+
+// errNoDeadlineSupport is returned by the deadline methods of a sysSocket
+// which is not backed by an *os.File, such as the bound (pre-Accept)
+// socket.
+var errNoDeadlineSupport = errors.New("alg: deadlines are only supported on accepted sockets")
+
 // A sysSocket is a socket which uses system calls for socket operations.
+//
+// Accepted operation sockets are additionally backed by an *os.File (f),
+// which registers the fd with the Go runtime's poller so that deadlines
+// work and so that blocking Reads can be interrupted safely by closing f.
+// Raw, non-pollable syscalls such as vmsplice, splice, sendfile, and
+// sendmsg instead operate on dupFD, a duplicate of the same underlying
+// socket; cancellation closes dupFD to unblock any such syscall that is
+// currently in flight, without invalidating fd out from under the poller.
 type sysSocket struct {
-	fd int
+	fd    int
+	f     *os.File
+	dupFD int
+
+	// dupFDClose guards against Cancel and Close racing to close dupFD
+	// from different goroutines, and against Close re-closing an fd
+	// number the kernel may have already reassigned to something
+	// unrelated after a prior Cancel closed it.
+	dupFDClose    sync.Once
+	dupFDCloseErr error
+}
+
+// retryEAGAIN invokes op, a raw syscall which writes to fd and may return
+// EAGAIN because fd shares its non-blocking status with f (see sysSocket's
+// dupFD field): waiting for fd to become writable and retrying op until it
+// succeeds or fails with a different error.
+func retryEAGAIN(fd int, op func() (int64, error)) (int64, error) {
+	for {
+		n, err := op()
+		if err != unix.EAGAIN {
+			return n, err
+		}
+
+		if err := waitWritable(fd); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// waitWritable blocks until fd is ready for writing, retrying on EINTR.
+func waitWritable(fd int) error {
+	pfds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLOUT}}
+	for {
+		if _, err := unix.Poll(pfds, -1); err != unix.EINTR {
+			return err
+		}
+	}
 }
 
 func (s *sysSocket) Accept() (socket, error) {
@@ -263,18 +731,126 @@ func (s *sysSocket) Accept() (socket, error) {
 		return nil, syscall.Errno(errno)
 	}
 
+	dupFD, err := unix.Dup(int(fd))
+	if err != nil {
+		unix.Close(int(fd))
+		return nil, err
+	}
+
+	if err := unix.SetNonblock(int(fd), true); err != nil {
+		unix.Close(int(fd))
+		unix.Close(dupFD)
+		return nil, err
+	}
+
 	// A sysSocket produces more sysSockets.
 	return &sysSocket{
-		fd: int(fd),
+		fd:    int(fd),
+		f:     os.NewFile(fd, "alg"),
+		dupFD: dupFD,
 	}, nil
 }
 func (s *sysSocket) Bind(sa unix.Sockaddr) error { return unix.Bind(s.fd, sa) }
-func (s *sysSocket) Close() error                { return unix.Close(s.fd) }
-func (s *sysSocket) FD() int                     { return s.fd }
+func (s *sysSocket) Close() error {
+	var err error
+	if s.f != nil {
+		err = s.f.Close()
+	} else {
+		err = unix.Close(s.fd)
+	}
+
+	if cerr := s.closeDupFD(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// closeDupFD closes dupFD at most once, so that Cancel and a later Close
+// can't race to close it from different goroutines, and so that Close
+// doesn't try to close dupFD a second time after Cancel already did.
+func (s *sysSocket) closeDupFD() error {
+	s.dupFDClose.Do(func() {
+		if s.dupFD >= 0 {
+			s.dupFDCloseErr = unix.Close(s.dupFD)
+		}
+	})
+
+	return s.dupFDCloseErr
+}
+
+// FD returns the file descriptor used for raw, potentially blocking
+// syscalls (vmsplice, splice, sendfile, sendmsg). For accepted sockets,
+// this is the duplicate fd that Cancel closes to interrupt such syscalls.
+func (s *sysSocket) FD() int {
+	if s.dupFD >= 0 {
+		return s.dupFD
+	}
+	return s.fd
+}
 func (s *sysSocket) Sendto(p []byte, flags int, to unix.Sockaddr) error {
 	return unix.Sendto(s.fd, p, flags, to)
 }
-func (s *sysSocket) Read(p []byte) (int, error) { return unix.Read(s.fd, p) }
+func (s *sysSocket) Read(p []byte) (int, error) {
+	if s.f != nil {
+		return s.f.Read(p)
+	}
+	return unix.Read(s.fd, p)
+}
+func (s *sysSocket) Sendmsg(p, oob []byte, flags int) error {
+	return unix.Sendmsg(s.FD(), p, oob, nil, flags)
+}
+func (s *sysSocket) SetsockoptKey(key []byte) error {
+	return unix.SetsockoptString(s.fd, unix.SOL_ALG, unix.ALG_SET_KEY, string(key))
+}
+func (s *sysSocket) SetsockoptAEADAuthSize(size int) error {
+	_, _, errno := unix.Syscall6(
+		unix.SYS_SETSOCKOPT,
+		uintptr(s.fd),
+		uintptr(unix.SOL_ALG),
+		uintptr(unix.ALG_SET_AEAD_AUTHSIZE),
+		0,
+		uintptr(size),
+		0,
+	)
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+func (s *sysSocket) SetDeadline(t time.Time) error {
+	if s.f == nil {
+		return errNoDeadlineSupport
+	}
+	return s.f.SetDeadline(t)
+}
+func (s *sysSocket) SetReadDeadline(t time.Time) error {
+	if s.f == nil {
+		return errNoDeadlineSupport
+	}
+	return s.f.SetReadDeadline(t)
+}
+func (s *sysSocket) SetWriteDeadline(t time.Time) error {
+	if s.f == nil {
+		return errNoDeadlineSupport
+	}
+	return s.f.SetWriteDeadline(t)
+}
+
+// Cancel interrupts any raw syscall currently blocked on this socket's
+// dupFD, and unblocks any pending poller-registered Read, by closing dupFD
+// and expiring f's deadlines. It does not close the socket itself; Close
+// must still be called to release its resources.
+func (s *sysSocket) Cancel() error {
+	if s.f != nil {
+		past := time.Unix(0, 0)
+		s.f.SetReadDeadline(past)
+		s.f.SetWriteDeadline(past)
+	}
+
+	return s.closeDupFD()
+}
 
 func newPipe() ([2]pipe, error) {
 	var pipes [2]int
@@ -290,6 +866,7 @@ func newPipe() ([2]pipe, error) {
 
 type pipe interface {
 	Splice(out, size, flags int) (int64, error)
+	SpliceFrom(in, size, flags int) (int64, error)
 	Vmsplice(b []byte, flags int) (int, error)
 }
 
@@ -301,6 +878,10 @@ func (p *sysPipe) Splice(out, size, flags int) (int64, error) {
 	return unix.Splice(p.fd, nil, out, nil, size, flags)
 }
 
+func (p *sysPipe) SpliceFrom(in, size, flags int) (int64, error) {
+	return unix.Splice(in, nil, p.fd, nil, size, flags)
+}
+
 func (p *sysPipe) Vmsplice(b []byte, flags int) (int, error) {
 	iov := unix.Iovec{
 		Base: &b[0],
@@ -318,9 +899,102 @@ type writerOnly struct {
 	io.Writer
 }
 
-// Fallback implementation of io.ReaderFrom's ReadFrom, when os.File isn't
-// applicable.
+// Fallback implementation of io.ReaderFrom's ReadFrom, when a zero-copy
+// transfer isn't applicable.
 func genericReadFrom(w io.Writer, r io.Reader) (n int64, err error) {
 	// Use wrapper to hide existing r.ReadFrom from io.Copy.
 	return io.Copy(writerOnly{w}, r)
 }
+
+// procCrypto is the location of the kernel's crypto algorithm registry.
+const procCrypto = "/proc/crypto"
+
+// Algorithms parses /proc/crypto and returns the set of algorithms
+// registered with the running kernel.
+func Algorithms() ([]AlgInfo, error) {
+	f, err := os.Open(procCrypto)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseCrypto(f)
+}
+
+// parseCrypto parses the /proc/crypto format: algorithms are described by
+// blocks of "key : value" lines, separated by blank lines.
+func parseCrypto(r io.Reader) ([]AlgInfo, error) {
+	var (
+		infos  []AlgInfo
+		fields map[string]string
+	)
+
+	flush := func() {
+		if fields == nil {
+			return
+		}
+		infos = append(infos, algInfoFromFields(fields))
+		fields = nil
+	}
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			flush()
+			continue
+		}
+
+		key, value, ok := splitCryptoLine(line)
+		if !ok {
+			continue
+		}
+
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[key] = value
+	}
+	flush()
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return infos, nil
+}
+
+// splitCryptoLine splits a "key : value" line from /proc/crypto.
+func splitCryptoLine(line string) (key, value string, ok bool) {
+	parts := strings.SplitN(line, ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
+
+// algInfoFromFields builds an AlgInfo from the fields of a single
+// /proc/crypto block.
+func algInfoFromFields(fields map[string]string) AlgInfo {
+	return AlgInfo{
+		Name:       fields["name"],
+		Driver:     fields["driver"],
+		Module:     fields["module"],
+		Type:       fields["type"],
+		Priority:   atoiOrZero(fields["priority"]),
+		Internal:   fields["internal"] == "yes",
+		Async:      fields["async"] == "yes",
+		BlockSize:  atoiOrZero(fields["blocksize"]),
+		DigestSize: atoiOrZero(fields["digestsize"]),
+		IVSize:     atoiOrZero(fields["ivsize"]),
+		KeySize:    atoiOrZero(fields["max keysize"]),
+	}
+}
+
+// atoiOrZero parses s as an integer, returning 0 if s is empty or
+// malformed.
+func atoiOrZero(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
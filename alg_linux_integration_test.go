@@ -4,7 +4,12 @@ package alg_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
@@ -13,6 +18,7 @@ import (
 	"io"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/mdlayher/alg"
 )
@@ -48,6 +54,259 @@ func TestSHA256Equal(t *testing.T) {
 	})
 }
 
+func TestHMACSHA256Equal(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	c, err := alg.Dial(alg.HMAC_SHA256(key))
+	if err != nil {
+		t.Fatalf("failed to dial kernel: %v", err)
+	}
+	defer c.Close()
+
+	algh, err := c.Hash()
+	if err != nil {
+		t.Fatalf("failed to make hash: %v", err)
+	}
+	defer algh.Close()
+
+	const n = 8192
+
+	stdh := hmac.New(sha256.New, key)
+	w := io.MultiWriter(stdh, algh)
+	r := limitReader(n)
+
+	if nn, err := io.Copy(w, r); err != nil || int64(nn) != n {
+		t.Fatalf("failed to copy: %q\n- want bytes: %d\n-  got bytes: %d",
+			err, n, nn)
+	}
+
+	if want, got := stdh.Sum(nil), algh.Sum(nil); !bytes.Equal(want, got) {
+		t.Fatalf("unexpected hash sum:\n- std: %x\n- alg: %x", want, got)
+	}
+}
+
+func TestReadFromPipeEqual(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer pr.Close()
+
+	const n = 8192
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("failed to generate data: %v", err)
+	}
+
+	go func() {
+		defer pw.Close()
+
+		// Write in small, delayed chunks so that reading from pr, a
+		// real *os.File backed by a non-blocking fd, observes EAGAIN
+		// and must wait for more data to arrive rather than always
+		// finding some immediately available.
+		for i := 0; i < len(data); i += 64 {
+			end := i + 64
+			if end > len(data) {
+				end = len(data)
+			}
+
+			time.Sleep(time.Millisecond)
+			if _, err := pw.Write(data[i:end]); err != nil {
+				return
+			}
+		}
+	}()
+
+	c, err := alg.Dial(alg.SHA256())
+	if err != nil {
+		t.Fatalf("failed to dial kernel: %v", err)
+	}
+	defer c.Close()
+
+	algh, err := c.Hash()
+	if err != nil {
+		t.Fatalf("failed to make hash: %v", err)
+	}
+	defer algh.Close()
+
+	if nn, err := io.Copy(algh, pr); err != nil || nn != n {
+		t.Fatalf("failed to copy: %q\n- want bytes: %d\n-  got bytes: %d",
+			err, n, nn)
+	}
+
+	want := sha256.Sum256(data)
+	if got := algh.Sum(nil); !bytes.Equal(want[:], got) {
+		t.Fatalf("unexpected hash sum:\n- want: %x\n-  got: %x", want, got)
+	}
+}
+
+func TestHashPoolSubmit(t *testing.T) {
+	c, err := alg.Dial(alg.SHA256())
+	if err != nil {
+		t.Fatalf("failed to dial kernel: %v", err)
+	}
+	defer c.Close()
+
+	bh, err := c.HashPool(4)
+	if err != nil {
+		t.Fatalf("failed to create hash pool: %v", err)
+	}
+	defer bh.Close()
+
+	const n = 16
+
+	results := make([]<-chan alg.Result, n)
+	for i := 0; i < n; i++ {
+		data := []byte(fmt.Sprintf("job %d", i))
+		results[i] = bh.Submit(uint64(i), data)
+	}
+
+	for i := 0; i < n; i++ {
+		data := []byte(fmt.Sprintf("job %d", i))
+
+		want := sha256.Sum256(data)
+		res := <-results[i]
+		if res.Err != nil {
+			t.Fatalf("unexpected error for job %d: %v", res.ID, res.Err)
+		}
+
+		if uint64(i) != res.ID {
+			t.Fatalf("unexpected job ID:\n- want: %d\n-  got: %d", i, res.ID)
+		}
+
+		if !bytes.Equal(want[:], res.Sum) {
+			t.Fatalf("unexpected sum for job %d:\n- want: %x\n-  got: %x", res.ID, want, res.Sum)
+		}
+	}
+}
+
+func TestAESCBCEqual(t *testing.T) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatalf("failed to generate IV: %v", err)
+	}
+
+	plaintext := bytes.Repeat([]byte("a"), 4*aes.BlockSize)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("failed to create AES cipher: %v", err)
+	}
+
+	want := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(want, plaintext)
+
+	c, err := alg.Dial(alg.AES_CBC(key))
+	if err != nil {
+		t.Fatalf("failed to dial kernel: %v", err)
+	}
+	defer c.Close()
+
+	algc, err := c.Cipher()
+	if err != nil {
+		t.Fatalf("failed to create cipher: %v", err)
+	}
+	defer algc.Close()
+
+	got, err := algc.Encrypt(iv, nil, plaintext)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Fatalf("unexpected ciphertext:\n- want: %x\n-  got: %x", want, got)
+	}
+}
+
+func TestAESGCMEqual(t *testing.T) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	aad := []byte("additional authenticated data")
+	plaintext := bytes.Repeat([]byte("a"), 4*aes.BlockSize)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("failed to create AES cipher: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("failed to create GCM: %v", err)
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatalf("failed to generate IV: %v", err)
+	}
+
+	want := gcm.Seal(nil, iv, plaintext, aad)
+
+	c, err := alg.Dial(alg.AES_GCM(key, len(aad), gcm.Overhead()))
+	if err != nil {
+		t.Fatalf("failed to dial kernel: %v", err)
+	}
+	defer c.Close()
+
+	algc, err := c.Cipher()
+	if err != nil {
+		t.Fatalf("failed to create cipher: %v", err)
+	}
+	defer algc.Close()
+
+	got, err := algc.Encrypt(iv, aad, plaintext)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Fatalf("unexpected ciphertext:\n- want: %x\n-  got: %x", want, got)
+	}
+
+	gotPlaintext, err := algc.Decrypt(iv, aad, got)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+
+	if !bytes.Equal(plaintext, gotPlaintext) {
+		t.Fatalf("unexpected plaintext:\n- want: %x\n-  got: %x", plaintext, gotPlaintext)
+	}
+}
+
+func TestHashContextCancel(t *testing.T) {
+	c, err := alg.Dial(alg.SHA256())
+	if err != nil {
+		t.Fatalf("failed to dial kernel: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	algh, err := c.HashContext(ctx)
+	if err != nil {
+		t.Fatalf("failed to make hash: %v", err)
+	}
+	defer algh.Close()
+
+	// Canceling ctx before any data is written should unblock and fail a
+	// subsequent Write, rather than hanging forever.
+	cancel()
+
+	if _, err := algh.Write([]byte("hello world")); err == nil {
+		t.Fatal("expected an error writing to a canceled Hash, got none")
+	}
+}
+
 func BenchmarkMD5(b *testing.B) {
 	withHash(b, "md5", func(algh hash.Hash) {
 		benchmarkHashes(b, md5.New(), algh)
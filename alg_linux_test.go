@@ -4,11 +4,72 @@ package alg
 
 import (
 	"encoding/hex"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/sys/unix"
 )
 
+func TestParseCrypto(t *testing.T) {
+	const input = `name         : sha256
+driver       : sha256-generic
+module       : kernel
+priority     : 100
+refcnt       : 1
+selftest     : passed
+internal     : no
+type         : shash
+blocksize    : 64
+digestsize   : 32
+
+name         : cbc(aes)
+driver       : cbc(aes-generic)
+module       : kernel
+priority     : 100
+refcnt       : 1
+selftest     : passed
+internal     : no
+type         : skcipher
+async        : no
+blocksize    : 16
+ivsize       : 16
+max keysize  : 32
+`
+
+	got, err := parseCrypto(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	want := []AlgInfo{
+		{
+			Name:       "sha256",
+			Driver:     "sha256-generic",
+			Module:     "kernel",
+			Type:       "shash",
+			Priority:   100,
+			BlockSize:  64,
+			DigestSize: 32,
+		},
+		{
+			Name:      "cbc(aes)",
+			Driver:    "cbc(aes-generic)",
+			Module:    "kernel",
+			Type:      "skcipher",
+			Priority:  100,
+			BlockSize: 16,
+			IVSize:    16,
+			KeySize:   32,
+		},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("unexpected algorithms:\n- want: %#v\n-  got: %#v", want, got)
+	}
+}
+
 //func TestLinuxConn_bind(t *testing.T) {
 //	addr := &unix.SockaddrALG{
 //		Type: "hash",
@@ -119,3 +180,10 @@ func (s *noopSocket) Close() error                                       { retur
 func (s *noopSocket) FD() int                                            { return 0 }
 func (s *noopSocket) Read(p []byte) (int, error)                         { return 0, nil }
 func (s *noopSocket) Sendto(p []byte, flags int, to unix.Sockaddr) error { return nil }
+func (s *noopSocket) Sendmsg(p, oob []byte, flags int) error             { return nil }
+func (s *noopSocket) SetsockoptKey(key []byte) error                    { return nil }
+func (s *noopSocket) SetsockoptAEADAuthSize(size int) error             { return nil }
+func (s *noopSocket) SetDeadline(t time.Time) error                     { return nil }
+func (s *noopSocket) SetReadDeadline(t time.Time) error                 { return nil }
+func (s *noopSocket) SetWriteDeadline(t time.Time) error                { return nil }
+func (s *noopSocket) Cancel() error                                     { return nil }
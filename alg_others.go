@@ -3,6 +3,7 @@
 package alg
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 )
@@ -31,3 +32,28 @@ func (c *conn) Close() error {
 func (c *conn) Hash(size, blockSize int) (Hash, error) {
 	return nil, errUnimplemented
 }
+
+// HashContext always returns an error.
+func (c *conn) HashContext(ctx context.Context, size, blockSize int) (Hash, error) {
+	return nil, errUnimplemented
+}
+
+// Cipher always returns an error.
+func (c *conn) Cipher(ivSize, assocSize, authSize int) (Cipher, error) {
+	return nil, errUnimplemented
+}
+
+// CipherContext always returns an error.
+func (c *conn) CipherContext(ctx context.Context, ivSize, assocSize, authSize int) (Cipher, error) {
+	return nil, errUnimplemented
+}
+
+// HashPool always returns an error.
+func (c *conn) HashPool(n, size, blockSize int) (BatchHasher, error) {
+	return nil, errUnimplemented
+}
+
+// Algorithms always returns an error.
+func Algorithms() ([]AlgInfo, error) {
+	return nil, errUnimplemented
+}
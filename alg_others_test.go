@@ -3,6 +3,7 @@
 package alg
 
 import (
+	"context"
 	"testing"
 )
 
@@ -20,6 +21,31 @@ func TestOthersConnUnimplemented(t *testing.T) {
 			want, got)
 	}
 
+	if _, got := c.HashContext(context.Background(), 0, 0); want != got {
+		t.Fatalf("unexpected error during c.HashContext:\n- want: %v\n-  got: %v",
+			want, got)
+	}
+
+	if _, got := c.Cipher(0, 0, 0); want != got {
+		t.Fatalf("unexpected error during c.Cipher:\n- want: %v\n-  got: %v",
+			want, got)
+	}
+
+	if _, got := c.CipherContext(context.Background(), 0, 0, 0); want != got {
+		t.Fatalf("unexpected error during c.CipherContext:\n- want: %v\n-  got: %v",
+			want, got)
+	}
+
+	if _, got := c.HashPool(1, 0, 0); want != got {
+		t.Fatalf("unexpected error during c.HashPool:\n- want: %v\n-  got: %v",
+			want, got)
+	}
+
+	if _, got := Algorithms(); want != got {
+		t.Fatalf("unexpected error during Algorithms:\n- want: %v\n-  got: %v",
+			want, got)
+	}
+
 	if got := c.Close(); want != got {
 		t.Fatalf("unexpected error during c.Close:\n- want: %v\n-  got: %v",
 			want, got)
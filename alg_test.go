@@ -0,0 +1,73 @@
+package alg
+
+import "testing"
+
+func TestRegisterHash(t *testing.T) {
+	const name = "crc32c"
+
+	if _, _, ok := hashSizes(name); ok {
+		t.Fatalf("hash %q should not be registered yet", name)
+	}
+
+	RegisterHash(name, 4, 4)
+
+	size, blockSize, ok := hashSizes(name)
+	if !ok {
+		t.Fatalf("hash %q should be registered", name)
+	}
+
+	if want, got := 4, size; want != got {
+		t.Fatalf("unexpected size:\n- want: %d\n-  got: %d", want, got)
+	}
+	if want, got := 4, blockSize; want != got {
+		t.Fatalf("unexpected block size:\n- want: %d\n-  got: %d", want, got)
+	}
+}
+
+func TestAlgTypeMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		procType string
+		typ      string
+		want     bool
+	}{
+		{
+			name:     "shash matches hash",
+			procType: "shash",
+			typ:      typeHash,
+			want:     true,
+		},
+		{
+			name:     "ahash matches hash",
+			procType: "ahash",
+			typ:      typeHash,
+			want:     true,
+		},
+		{
+			name:     "skcipher matches skcipher",
+			procType: "skcipher",
+			typ:      typeSkcipher,
+			want:     true,
+		},
+		{
+			name:     "aead matches aead",
+			procType: "aead",
+			typ:      typeAead,
+			want:     true,
+		},
+		{
+			name:     "hash does not match skcipher",
+			procType: "shash",
+			typ:      typeSkcipher,
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if want, got := tt.want, algTypeMatches(tt.procType, tt.typ); want != got {
+				t.Fatalf("unexpected result:\n- want: %v\n-  got: %v", want, got)
+			}
+		})
+	}
+}